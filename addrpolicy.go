@@ -0,0 +1,160 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+)
+
+type selectPolicy int
+
+const (
+	selectFirst selectPolicy = iota
+	selectRandom
+	selectRoundRobin
+	selectPreferPermanent
+	selectPreferPublic
+)
+
+type familyPolicy int
+
+const (
+	familyAny familyPolicy = iota
+	familyPreferIPv4
+	familyPreferIPv6
+)
+
+// selectState carries address selection state that must persist across
+// polls of a single interface, such as the roundRobin cursor.
+type selectState struct {
+	rr int
+}
+
+// parseAddrPolicy parses the 5th iface:label:strategy:interval:policy
+// field: one of first, random, roundRobin, preferPermanent, preferPublic
+// and/or preferIPv4, preferIPv6, any, joined with "+", e.g.
+// "preferPermanent+preferIPv6".
+func parseAddrPolicy(s string) (selectPolicy, familyPolicy, error) {
+	sel := selectFirst
+	fam := familyAny
+	if s == "" {
+		return sel, fam, nil
+	}
+	for _, tok := range strings.Split(s, "+") {
+		switch tok {
+		case "first":
+			sel = selectFirst
+		case "random":
+			sel = selectRandom
+		case "roundRobin":
+			sel = selectRoundRobin
+		case "preferPermanent":
+			sel = selectPreferPermanent
+		case "preferPublic":
+			sel = selectPreferPublic
+		case "preferIPv4":
+			fam = familyPreferIPv4
+		case "preferIPv6":
+			fam = familyPreferIPv6
+		case "any":
+			fam = familyAny
+		default:
+			return sel, fam, fmt.Errorf("%w: %s", errInvalidSpecification, tok)
+		}
+	}
+	return sel, fam, nil
+}
+
+// selectAddrs orders and filters addr according to ift's family and select
+// policies, so resolv() always tries the same address first across polls
+// instead of whatever order the kernel returned it in.
+func selectAddrs(ift ifT, addr []net.IP, st *selectState) []net.IP {
+	addr = applyFamilyPolicy(addr, ift.family)
+	if len(addr) == 0 {
+		return addr
+	}
+
+	switch ift.sel {
+	case selectRandom:
+		out := append([]net.IP{}, addr...)
+		rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+		return out
+	case selectRoundRobin:
+		out := append([]net.IP{}, addr...)
+		n := st.rr % len(out)
+		st.rr++
+		return append(out[n:], out[:n]...)
+	case selectPreferPermanent:
+		return preferPermanent(ift.name, addr)
+	case selectPreferPublic:
+		return preferPublic(addr)
+	default:
+		return addr
+	}
+}
+
+// applyFamilyPolicy stably reorders addr so the preferred family is tried
+// first, keeping the other family as a fallback rather than discarding it.
+func applyFamilyPolicy(addr []net.IP, fam familyPolicy) []net.IP {
+	if fam == familyAny || len(addr) < 2 {
+		return addr
+	}
+	out := append([]net.IP{}, addr...)
+	sort.SliceStable(out, func(i, j int) bool {
+		iv4 := out[i].To4() != nil
+		jv4 := out[j].To4() != nil
+		if iv4 == jv4 {
+			return false
+		}
+		if fam == familyPreferIPv4 {
+			return iv4
+		}
+		return !iv4
+	})
+	return out
+}
+
+// preferPublic filters out RFC 1918/ULA addresses, falling back to the
+// original set if nothing public-facing remains.
+func preferPublic(addr []net.IP) []net.IP {
+	var public []net.IP
+	for _, ip := range addr {
+		if !ip.IsPrivate() {
+			public = append(public, ip)
+		}
+	}
+	if len(public) == 0 {
+		return addr
+	}
+	return public
+}
+
+// preferPermanent filters out IPv6 temporary/deprecated addresses on name,
+// falling back to the original set if nothing permanent remains.
+func preferPermanent(name string, addr []net.IP) []net.IP {
+	var permanent []net.IP
+	for _, ip := range addr {
+		if !isTemporaryAddr(name, ip) {
+			permanent = append(permanent, ip)
+		}
+	}
+	if len(permanent) == 0 {
+		return addr
+	}
+	return permanent
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseAddrPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		sel     selectPolicy
+		fam     familyPolicy
+		wantErr bool
+	}{
+		{in: "", sel: selectFirst, fam: familyAny},
+		{in: "preferPermanent+preferIPv6", sel: selectPreferPermanent, fam: familyPreferIPv6},
+		{in: "roundRobin", sel: selectRoundRobin, fam: familyAny},
+		{in: "preferPublic+preferIPv4", sel: selectPreferPublic, fam: familyPreferIPv4},
+		{in: "any", sel: selectFirst, fam: familyAny},
+		{in: "bogus", wantErr: true},
+	}
+	for _, tt := range tests {
+		sel, fam, err := parseAddrPolicy(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAddrPolicy(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseAddrPolicy(%q): unexpected error: %s", tt.in, err)
+			continue
+		}
+		if sel != tt.sel || fam != tt.fam {
+			t.Errorf("parseAddrPolicy(%q) = %v, %v, want %v, %v", tt.in, sel, fam, tt.sel, tt.fam)
+		}
+	}
+}
+
+func TestApplyFamilyPolicyStable(t *testing.T) {
+	v4a := net.ParseIP("192.0.2.1")
+	v4b := net.ParseIP("192.0.2.2")
+	v6a := net.ParseIP("2001:db8::1")
+	v6b := net.ParseIP("2001:db8::2")
+	addr := []net.IP{v4a, v6a, v4b, v6b}
+
+	out := applyFamilyPolicy(addr, familyPreferIPv6)
+	want := []string{"2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2"}
+	for i, ip := range out {
+		if ip.String() != want[i] {
+			t.Fatalf("preferIPv6: got %v, want %v", out, want)
+		}
+	}
+
+	out = applyFamilyPolicy(addr, familyPreferIPv4)
+	want = []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "2001:db8::2"}
+	for i, ip := range out {
+		if ip.String() != want[i] {
+			t.Fatalf("preferIPv4: got %v, want %v", out, want)
+		}
+	}
+
+	out = applyFamilyPolicy(addr, familyAny)
+	for i, ip := range out {
+		if !ip.Equal(addr[i]) {
+			t.Fatalf("familyAny: got %v, want original order %v", out, addr)
+		}
+	}
+}
+
+func TestSelectAddrsRoundRobinStableAcrossPolls(t *testing.T) {
+	addr := []net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("192.0.2.2"),
+		net.ParseIP("192.0.2.3"),
+	}
+	ift := ifT{sel: selectRoundRobin}
+	st := new(selectState)
+
+	first := selectAddrs(ift, addr, st)
+	if !first[0].Equal(addr[0]) {
+		t.Fatalf("first poll: got %v, want lead %v", first, addr[0])
+	}
+	second := selectAddrs(ift, addr, st)
+	if !second[0].Equal(addr[1]) {
+		t.Fatalf("second poll: got %v, want lead %v", second, addr[1])
+	}
+	third := selectAddrs(ift, addr, st)
+	if !third[0].Equal(addr[2]) {
+		t.Fatalf("third poll: got %v, want lead %v", third, addr[2])
+	}
+	fourth := selectAddrs(ift, addr, st)
+	if !fourth[0].Equal(addr[0]) {
+		t.Fatalf("fourth poll: got %v, want wraparound to %v", fourth, addr[0])
+	}
+}
+
+func TestSelectAddrsRandomPreservesSet(t *testing.T) {
+	addr := []net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("192.0.2.2"),
+		net.ParseIP("192.0.2.3"),
+	}
+	out := selectAddrs(ifT{sel: selectRandom}, addr, new(selectState))
+	if len(out) != len(addr) {
+		t.Fatalf("selectRandom: got %d addrs, want %d", len(out), len(addr))
+	}
+	for _, a := range addr {
+		found := false
+		for _, o := range out {
+			if o.Equal(a) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("selectRandom: %v missing from result %v", a, out)
+		}
+	}
+}
+
+func TestPreferPublic(t *testing.T) {
+	priv := net.ParseIP("192.168.1.1")
+	pub := net.ParseIP("203.0.113.1")
+
+	out := preferPublic([]net.IP{priv, pub})
+	if len(out) != 1 || !out[0].Equal(pub) {
+		t.Fatalf("preferPublic: got %v, want only %v", out, pub)
+	}
+
+	out = preferPublic([]net.IP{priv})
+	if len(out) != 1 || !out[0].Equal(priv) {
+		t.Fatalf("preferPublic: all-private fallback got %v, want %v", out, priv)
+	}
+}
+
+func TestPreferPermanentFallsBackWhenUnknown(t *testing.T) {
+	addr := []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2")}
+	out := preferPermanent("nonexistent0", addr)
+	if len(out) != len(addr) {
+		t.Fatalf("preferPermanent: got %v, want unfiltered fallback %v", out, addr)
+	}
+}
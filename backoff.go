@@ -0,0 +1,56 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	backoffInitial = 1 * time.Second
+	backoffMax     = 2 * time.Minute
+	backoffRetries = 5
+)
+
+// withBackoff retries fn with exponential backoff and full jitter between
+// attempts, giving up and returning the last error after backoffRetries
+// attempts.
+func withBackoff(ctx context.Context, fn func() error) error {
+	delay := backoffInitial
+
+	var err error
+	for attempt := 0; attempt < backoffRetries; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == backoffRetries-1 {
+			break
+		}
+
+		jitter := time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jitter):
+		}
+
+		delay *= 2
+		if delay > backoffMax {
+			delay = backoffMax
+		}
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var errNoBootstrapResolvers = errors.New("no bootstrap resolvers configured")
+
+type bootstrapCacheEntry struct {
+	addr    string
+	expires time.Time
+}
+
+// bootstrapResolver resolves hostname-form nameservers through a fixed list
+// of IP-literal resolvers, so that strategies setting net.Resolver.PreferGo
+// never bootstrap through /etc/resolv.conf to find their own server.
+// Resolved addresses are cached by the answer's TTL and are safe for
+// concurrent use across interfaces.
+type bootstrapResolver struct {
+	servers []string
+
+	mu    sync.Mutex
+	cache map[string]bootstrapCacheEntry
+}
+
+func newBootstrapResolver(servers []string) *bootstrapResolver {
+	return &bootstrapResolver{
+		servers: servers,
+		cache:   make(map[string]bootstrapCacheEntry),
+	}
+}
+
+// resolve looks up the host in a "host:port" nameserver spec, returning
+// "ip:port". IP-literal hosts are returned unchanged.
+func (b *bootstrapResolver) resolve(ctx context.Context, hostport string) (string, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return hostport, nil
+	}
+
+	if addr, ok := b.lookupCache(host); ok {
+		return net.JoinHostPort(addr, port), nil
+	}
+
+	addr, ttl, err := b.exchange(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	b.store(host, addr, ttl)
+	return net.JoinHostPort(addr, port), nil
+}
+
+func (b *bootstrapResolver) lookupCache(host string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.cache[host]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.addr, true
+}
+
+func (b *bootstrapResolver) store(host, addr string, ttl time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cache[host] = bootstrapCacheEntry{addr: addr, expires: time.Now().Add(ttl)}
+}
+
+// exchange queries each configured bootstrap resolver in turn, retrying
+// across the list until one answers.
+func (b *bootstrapResolver) exchange(ctx context.Context, host string) (string, time.Duration, error) {
+	if len(b.servers) == 0 {
+		return "", 0, errNoBootstrapResolvers
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(host), dns.TypeA)
+
+	c := new(dns.Client)
+
+	var lastErr error
+	for _, server := range b.servers {
+		r, _, err := c.ExchangeContext(ctx, m, server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, rr := range r.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				return a.A.String(), time.Duration(a.Hdr.Ttl) * time.Second, nil
+			}
+		}
+		lastErr = errNoValidAddresses
+	}
+	return "", 0, lastErr
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startTestResolver runs a local DNS server answering every A query for
+// qname with addr/ttl, counting how many queries it has handled.
+func startTestResolver(t *testing.T, qname, addr string, ttl uint32) (server string, hits *int32) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+
+	var n int32
+	mux := dns.NewServeMux()
+	mux.HandleFunc(qname, func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&n, 1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN A %s", qname, ttl, addr))
+		if err == nil {
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	srv := &dns.Server{PacketConn: pc, Handler: mux}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	return pc.LocalAddr().String(), &n
+}
+
+func TestBootstrapResolverIPLiteralPassthrough(t *testing.T) {
+	b := newBootstrapResolver(nil)
+	got, err := b.resolve(context.Background(), "192.0.2.1:53")
+	if err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if got != "192.0.2.1:53" {
+		t.Fatalf("resolve: got %s, want 192.0.2.1:53", got)
+	}
+}
+
+func TestBootstrapResolverNoServersConfigured(t *testing.T) {
+	b := newBootstrapResolver(nil)
+	if _, err := b.resolve(context.Background(), "example.com:443"); err == nil {
+		t.Fatal("resolve: expected error with no bootstrap servers")
+	}
+}
+
+func TestBootstrapResolverCachesByTTL(t *testing.T) {
+	server, hits := startTestResolver(t, "example.com.", "203.0.113.9", 3600)
+	b := newBootstrapResolver([]string{server})
+
+	for i := 0; i < 3; i++ {
+		got, err := b.resolve(context.Background(), "example.com:443")
+		if err != nil {
+			t.Fatalf("resolve: %s", err)
+		}
+		if got != "203.0.113.9:443" {
+			t.Fatalf("resolve: got %s, want 203.0.113.9:443", got)
+		}
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Fatalf("resolver hit %d times, want 1 (later calls should be cached)", *hits)
+	}
+}
+
+func TestBootstrapResolverExpiresCache(t *testing.T) {
+	server, hits := startTestResolver(t, "example.com.", "203.0.113.9", 0)
+	b := newBootstrapResolver([]string{server})
+
+	if _, err := b.resolve(context.Background(), "example.com:443"); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if _, err := b.resolve(context.Background(), "example.com:443"); err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if atomic.LoadInt32(hits) < 2 {
+		t.Fatalf("resolver hit %d times, want >= 2 (ttl=0 entries must not be cached)", *hits)
+	}
+}
+
+func TestBootstrapResolverRetriesAcrossList(t *testing.T) {
+	// down is a closed listener address: nothing answers there, so the
+	// client should move on to the next configured server.
+	down, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %s", err)
+	}
+	downAddr := down.LocalAddr().String()
+	down.Close()
+
+	server, hits := startTestResolver(t, "example.com.", "198.51.100.7", 3600)
+	b := newBootstrapResolver([]string{downAddr, server})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got, err := b.resolve(ctx, "example.com:443")
+	if err != nil {
+		t.Fatalf("resolve: %s", err)
+	}
+	if got != "198.51.100.7:443" {
+		t.Fatalf("resolve: got %s, want 198.51.100.7:443", got)
+	}
+	if atomic.LoadInt32(hits) != 1 {
+		t.Fatalf("working resolver hit %d times, want 1", *hits)
+	}
+}
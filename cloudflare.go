@@ -0,0 +1,105 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"fmt"
+
+	cloudflare "github.com/cloudflare/cloudflare-go"
+)
+
+func init() {
+	registerPublisher(Cloudflare, newCloudflarePublisher)
+}
+
+// cloudflarePublisher publishes records using the Cloudflare v4 API.
+type cloudflarePublisher struct {
+	api    *cloudflare.API
+	domain string
+}
+
+func newCloudflarePublisher(argv *argvT) (Publisher, error) {
+	api, err := cloudflare.NewWithAPIToken(getenv("DNSUP_CLOUDFLARE_APIKEY", argv.apikey))
+	if err != nil {
+		return nil, err
+	}
+	return &cloudflarePublisher{api: api, domain: argv.domain}, nil
+}
+
+func (p *cloudflarePublisher) Publish(ctx context.Context, label, rtype string, ttl int, value string) error {
+	zoneID, err := p.api.ZoneIDByName(p.domain)
+	if err != nil {
+		return err
+	}
+	rc := cloudflare.ZoneIdentifier(zoneID)
+	name := recordName(label, p.domain)
+
+	records, _, err := p.api.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{
+		Type: rtype,
+		Name: name,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		_, err := p.api.CreateDNSRecord(ctx, rc, cloudflare.CreateDNSRecordParams{
+			Type:    rtype,
+			Name:    name,
+			Content: value,
+			TTL:     ttl,
+		})
+		return err
+	}
+
+	_, err = p.api.UpdateDNSRecord(ctx, rc, cloudflare.UpdateDNSRecordParams{
+		ID:      records[0].ID,
+		Type:    rtype,
+		Name:    name,
+		Content: value,
+		TTL:     ttl,
+	})
+	return err
+}
+
+func (p *cloudflarePublisher) Get(ctx context.Context, label string) (string, string, error) {
+	zoneID, err := p.api.ZoneIDByName(p.domain)
+	if err != nil {
+		return "", "", err
+	}
+	rc := cloudflare.ZoneIdentifier(zoneID)
+
+	records, _, err := p.api.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{
+		Name: recordName(label, p.domain),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	for _, record := range records {
+		if record.Type == "A" || record.Type == "AAAA" {
+			return record.Type, record.Content, nil
+		}
+	}
+	return "", "", errRecordNotFound
+}
+
+// recordName builds the fully qualified record name for label in domain,
+// treating "@" and "" as the zone apex.
+func recordName(label, domain string) string {
+	if label == "" || label == "@" {
+		return domain
+	}
+	return fmt.Sprintf("%s.%s", label, domain)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+
+	"github.com/dnsimple/dnsimple-go/v5/dnsimple"
+)
+
+func init() {
+	registerPublisher(DNSimple, newDNSimplePublisher)
+}
+
+// dnsimplePublisher publishes records using the DNSimple API. The API token
+// is read from DNSUP_DNSIMPLE_APIKEY (falling back to -apikey) and the
+// account ID from DNSUP_DNSIMPLE_ACCOUNT.
+type dnsimplePublisher struct {
+	client  *dnsimple.Client
+	account string
+	domain  string
+}
+
+func newDNSimplePublisher(argv *argvT) (Publisher, error) {
+	token := getenv("DNSUP_DNSIMPLE_APIKEY", argv.apikey)
+	return &dnsimplePublisher{
+		client:  dnsimple.NewClient(dnsimple.StaticTokenHTTPClient(context.Background(), token)),
+		account: getenv("DNSUP_DNSIMPLE_ACCOUNT", ""),
+		domain:  argv.domain,
+	}, nil
+}
+
+func (p *dnsimplePublisher) Publish(ctx context.Context, label, rtype string, ttl int, value string) error {
+	recordType := rtype
+	recordName := label
+	if label == "@" {
+		recordName = ""
+	}
+
+	resp, err := p.client.Zones.ListRecords(ctx, p.account, p.domain, &dnsimple.ZoneRecordListOptions{
+		Name: &recordName,
+		Type: &recordType,
+	})
+	if err != nil {
+		return err
+	}
+
+	attrs := dnsimple.ZoneRecordAttributes{
+		Type:    recordType,
+		Name:    &recordName,
+		Content: value,
+		TTL:     ttl,
+	}
+
+	if len(resp.Data) == 0 {
+		_, err := p.client.Zones.CreateRecord(ctx, p.account, p.domain, attrs)
+		return err
+	}
+
+	_, err = p.client.Zones.UpdateRecord(ctx, p.account, p.domain, resp.Data[0].ID, attrs)
+	return err
+}
+
+func (p *dnsimplePublisher) Get(ctx context.Context, label string) (string, string, error) {
+	recordName := label
+	if label == "@" {
+		recordName = ""
+	}
+
+	resp, err := p.client.Zones.ListRecords(ctx, p.account, p.domain, &dnsimple.ZoneRecordListOptions{
+		Name: &recordName,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, record := range resp.Data {
+		if record.Type == "A" || record.Type == "AAAA" {
+			return record.Type, record.Content, nil
+		}
+	}
+	return "", "", errRecordNotFound
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+func init() {
+	registerPublisher(Gandi, newGandiPublisher)
+}
+
+// gandiPublisher publishes records using the Gandi LiveDNS API.
+type gandiPublisher struct {
+	domain string
+	apikey string
+}
+
+func newGandiPublisher(argv *argvT) (Publisher, error) {
+	return &gandiPublisher{
+		domain: argv.domain,
+		apikey: argv.apikey,
+	}, nil
+}
+
+func (p *gandiPublisher) Publish(ctx context.Context, label, rtype string, ttl int, value string) error {
+	u := fmt.Sprintf("https://dns.api.gandi.net/api/v5/domains/%s/records/%s/%s",
+		p.domain,
+		label,
+		rtype,
+	)
+
+	h := make(http.Header)
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Api-Key", p.apikey)
+
+	body := fmt.Sprintf(
+		"{\"rrset_ttl\": %d, \"rrset_values\":[\"%s\"]}",
+		ttl,
+		value,
+	)
+
+	r, err := http.NewRequestWithContext(
+		ctx,
+		"PUT",
+		u,
+		bytes.NewBufferString(body),
+	)
+	if err != nil {
+		return err
+	}
+
+	r.Header = h
+
+	c := &http.Client{}
+	resp, err := c.Do(r)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	rbody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(rbody))
+	return nil
+}
+
+func (p *gandiPublisher) Get(ctx context.Context, label string) (string, string, error) {
+	u := fmt.Sprintf("https://dns.api.gandi.net/api/v5/domains/%s/records/%s", p.domain, label)
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", "", err
+	}
+	r.Header.Set("X-Api-Key", p.apikey)
+
+	c := &http.Client{}
+	resp, err := c.Do(r)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", "", errRecordNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("gandi: unexpected status: %s", resp.Status)
+	}
+
+	var rrsets []struct {
+		Type   string   `json:"rrset_type"`
+		Values []string `json:"rrset_values"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rrsets); err != nil {
+		return "", "", err
+	}
+
+	for _, rrset := range rrsets {
+		if (rrset.Type == "A" || rrset.Type == "AAAA") && len(rrset.Values) > 0 {
+			return rrset.Type, rrset.Values[0], nil
+		}
+	}
+	return "", "", errRecordNotFound
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// listenAndServe starts the optional status/metrics HTTP endpoint. It is
+// only reached when -listen is set, so the default binary behaviour is
+// unchanged.
+func (argv *argvT) listenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", argv.handleHealthz)
+	mux.HandleFunc("/status", argv.handleStatus)
+	mux.HandleFunc("/metrics", argv.handleMetrics)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (argv *argvT) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	_, _ = fmt.Fprintln(w, "ok")
+}
+
+func (argv *argvT) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(argv.status.snapshot())
+}
+
+func (argv *argvT) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	entries := argv.status.snapshot()
+
+	fmt.Fprintln(w, "# HELP dnsup_lookups_total Number of IP address lookups")
+	fmt.Fprintln(w, "# TYPE dnsup_lookups_total counter")
+	for _, e := range entries {
+		fmt.Fprintf(w, "dnsup_lookups_total{service=%q,label=%q} %d\n", argv.service, e.Label, e.Lookups)
+	}
+
+	fmt.Fprintln(w, "# HELP dnsup_lookup_errors_total Number of failed IP address lookups")
+	fmt.Fprintln(w, "# TYPE dnsup_lookup_errors_total counter")
+	for _, e := range entries {
+		fmt.Fprintf(w, "dnsup_lookup_errors_total{service=%q,label=%q} %d\n", argv.service, e.Label, e.LookupFails)
+	}
+
+	fmt.Fprintln(w, "# HELP dnsup_publishes_total Number of successful record publishes")
+	fmt.Fprintln(w, "# TYPE dnsup_publishes_total counter")
+	for _, e := range entries {
+		fmt.Fprintf(w, "dnsup_publishes_total{provider=%q,label=%q} %d\n", argv.provider, e.Label, e.Publishes)
+	}
+
+	fmt.Fprintln(w, "# HELP dnsup_publish_errors_total Number of failed record publishes")
+	fmt.Fprintln(w, "# TYPE dnsup_publish_errors_total counter")
+	for _, e := range entries {
+		fmt.Fprintf(w, "dnsup_publish_errors_total{provider=%q,label=%q} %d\n", argv.provider, e.Label, e.PublishFails)
+	}
+
+	fmt.Fprintln(w, "# HELP dnsup_current_ip Current discovered IP address")
+	fmt.Fprintln(w, "# TYPE dnsup_current_ip gauge")
+	for _, e := range entries {
+		if e.IP == "" {
+			continue
+		}
+		fmt.Fprintf(w, "dnsup_current_ip{label=%q,ip=%q} 1\n", e.Label, e.IP)
+	}
+
+	fmt.Fprintln(w, "# HELP dnsup_last_success_timestamp_seconds Unix timestamp of the last successful lookup or publish")
+	fmt.Fprintln(w, "# TYPE dnsup_last_success_timestamp_seconds gauge")
+	for _, e := range entries {
+		if e.LastSuccess.IsZero() {
+			continue
+		}
+		fmt.Fprintf(w, "dnsup_last_success_timestamp_seconds{label=%q} %d\n", e.Label, e.LastSuccess.Unix())
+	}
+
+	fmt.Fprintln(w, "# HELP dnsup_ttl_seconds Configured TTL of the last published record")
+	fmt.Fprintln(w, "# TYPE dnsup_ttl_seconds gauge")
+	for _, e := range entries {
+		if e.TTL == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "dnsup_ttl_seconds{label=%q} %d\n", e.Label, e.TTL)
+	}
+}
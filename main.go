@@ -14,20 +14,19 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net"
-	"net/http"
 	"os"
 	"path"
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 //go:generate stringer -type=Strategy
@@ -38,6 +37,9 @@ const (
 	inet6
 	resolv4
 	resolv6
+	dot4
+	dot6
+	doh
 )
 
 type ifT struct {
@@ -45,6 +47,8 @@ type ifT struct {
 	label    string
 	strategy Strategy
 	interval time.Duration
+	sel      selectPolicy
+	family   familyPolicy
 }
 
 type argvT struct {
@@ -52,6 +56,13 @@ type argvT struct {
 	domain       string
 	apikey       string
 	service      string
+	provider     string
+	publisher    Publisher
+	bootstrap    *bootstrapResolver
+	state        *stateStore
+	limiter      *rate.Limiter
+	status       *statusRegistry
+	listen       string
 	ttl          int
 	pollInterval time.Duration
 	dryrun       bool
@@ -74,6 +85,7 @@ var (
 	errInvalidAddress       = errors.New("invalid address")
 	errInvalidStrategy      = errors.New("invalid strategy")
 	errInvalidSpecification = errors.New("invalid specification")
+	errInvalidProvider      = errors.New("invalid provider")
 )
 
 func strategy(str string) (Strategy, error) {
@@ -90,6 +102,12 @@ func strategy(str string) (Strategy, error) {
 		return resolv4, nil
 	case "resolv6":
 		return resolv6, nil
+	case "dot4":
+		return dot4, nil
+	case "dot6":
+		return dot6, nil
+	case "doh":
+		return doh, nil
 	default:
 		return inet4, fmt.Errorf("%w: %s", errInvalidStrategy, str)
 	}
@@ -122,6 +140,23 @@ func toIf(arg []string, interval time.Duration) (ifs []ifT, err error) {
 				return ifs, err
 			}
 			ifs = append(ifs, ifT{name: x[0], label: x[1], strategy: s, interval: d})
+		case 5:
+			s, err := strategy(x[2])
+			if err != nil {
+				return ifs, err
+			}
+			d, err := time.ParseDuration(x[3])
+			if err != nil {
+				return ifs, err
+			}
+			sel, family, err := parseAddrPolicy(x[4])
+			if err != nil {
+				return ifs, err
+			}
+			ifs = append(ifs, ifT{
+				name: x[0], label: x[1], strategy: s,
+				interval: d, sel: sel, family: family,
+			})
 		default:
 			return ifs, fmt.Errorf("%w: %s", errInvalidSpecification, v)
 		}
@@ -141,6 +176,9 @@ func args() *argvT {
 		_, _ = fmt.Fprintf(os.Stderr, `%s v%s
 Usage: %s [<option>] <domain> <interface> <...>
 
+<domain> may be prefixed with a provider scheme, e.g. cloudflare://example.com,
+to override -provider.
+
 `, path.Base(os.Args[0]), version, os.Args[0])
 		flag.PrintDefaults()
 	}
@@ -153,6 +191,42 @@ Usage: %s [<option>] <domain> <interface> <...>
 		"Gandi APIKEY",
 	)
 
+	provider := flag.String(
+		"provider",
+		getenv("DNSUP_PROVIDER", Gandi),
+		"DNS publisher: gandi, cloudflare, route53, dnsimple, rfc2136",
+	)
+
+	bootstrap := flag.String(
+		"bootstrap",
+		getenv("DNSUP_BOOTSTRAP", ""),
+		"Comma separated IP-literal resolvers used to resolve nameserver hostnames, e.g. 1.1.1.1:53,8.8.8.8:53",
+	)
+
+	statePath := flag.String(
+		"state",
+		getenv("DNSUP_STATE", ""),
+		"Path to a file persisting last-published record state, used to reconcile after a restart",
+	)
+
+	rateLimit := flag.Float64(
+		"rate-limit",
+		1,
+		"Maximum publisher API calls per second",
+	)
+
+	rateBurst := flag.Int(
+		"rate-burst",
+		1,
+		"Maximum burst size for -rate-limit",
+	)
+
+	listen := flag.String(
+		"listen",
+		getenv("DNSUP_LISTEN", ""),
+		"Address for the HTTP status/metrics endpoint, e.g. :9099 (disabled if empty)",
+	)
+
 	service := flag.String(
 		"service",
 		Google,
@@ -209,23 +283,60 @@ Usage: %s [<option>] <domain> <interface> <...>
 		os.Exit(1)
 	}
 
-	return &argvT{
+	providerName, domain := splitProvider(flag.Args()[:1][0], strings.ToLower(*provider))
+
+	var bootstrapResolvers *bootstrapResolver
+	if *bootstrap != "" {
+		bootstrapResolvers = newBootstrapResolver(strings.Split(*bootstrap, ","))
+	}
+
+	var state *stateStore
+	if *statePath != "" {
+		state, err = loadStateStore(*statePath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	argv := &argvT{
 		dryrun:       *dryrun,
 		iface:        ifs,
-		domain:       flag.Args()[:1][0],
+		domain:       domain,
 		ttl:          *ttl,
 		pollInterval: *pollInterval,
 		apikey:       *apikey,
 		service:      *service,
+		provider:     providerName,
+		bootstrap:    bootstrapResolvers,
+		state:        state,
+		limiter:      rate.NewLimiter(rate.Limit(*rateLimit), *rateBurst),
+		status:       newStatusRegistry(),
+		listen:       *listen,
 		verbose:      *verbose,
 		stdout:       log.New(os.Stdout, "", 0),
 		stderr:       log.New(os.Stderr, "", 0),
 	}
+
+	publisher, err := newPublisher(providerName, argv)
+	if err != nil {
+		flag.Usage()
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	argv.publisher = publisher
+
+	return argv
 }
 
 func main() {
 	argv := args()
 	errch := make(chan error)
+	if argv.listen != "" {
+		go func() {
+			errch <- argv.listenAndServe(argv.listen)
+		}()
+	}
 	for _, ift := range argv.iface {
 		go argv.run(ift, errch)
 	}
@@ -239,7 +350,9 @@ func (argv *argvT) run(ift ifT, errch chan<- error) {
 	}
 
 	ticker := time.Tick(ift.interval)
-	var p string
+	p := argv.reconcile(context.Background(), ift)
+	st := new(selectState)
+	status := argv.status.get(ift)
 
 	for range ticker {
 		ip, err := ipaddr(ift.name)
@@ -247,7 +360,9 @@ func (argv *argvT) run(ift ifT, errch chan<- error) {
 			errch <- err
 			return
 		}
+		ip = selectAddrs(ift, ip, st)
 		n, err := argv.resolv(ift, ip)
+		status.recordLookup(n, err)
 		if err != nil {
 			argv.stderr.Printf("resolv: %+v: %s\n", ift, err)
 			continue
@@ -263,10 +378,48 @@ func (argv *argvT) run(ift ifT, errch chan<- error) {
 			continue
 		}
 		if err := argv.publish(ift.label, n); err != nil {
+			status.recordPublish(argv.ttl, err)
 			argv.stderr.Printf("publish: %+v: %s\n", ift, err)
 			continue
 		}
+		status.recordPublish(argv.ttl, nil)
+	}
+}
+
+func (argv *argvT) publish(label, ipaddr string) error {
+	ip := net.ParseIP(ipaddr)
+	if ip == nil {
+		return nil
+	}
+	rtype := "A"
+	if ip.To4() == nil {
+		rtype = "AAAA"
+	}
+
+	if argv.verbose > 0 {
+		argv.stderr.Printf("publish: %s: %s %s %s\n", argv.provider, label, rtype, ipaddr)
+	}
+
+	ctx := context.Background()
+	if err := argv.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	if err := withBackoff(ctx, func() error {
+		return argv.publisher.Publish(ctx, label, rtype, argv.ttl, ipaddr)
+	}); err != nil {
+		return err
+	}
+
+	if argv.state == nil {
+		return nil
 	}
+	return argv.state.set(label, recordState{
+		Type:      rtype,
+		Value:     ipaddr,
+		TTL:       argv.ttl,
+		UpdatedAt: time.Now(),
+	})
 }
 
 func ipaddr(name string) (n []net.IP, err error) {
@@ -305,19 +458,27 @@ func (argv *argvT) resolv(ift ifT, addr []net.IP) (string, error) {
 		case resolv4:
 			r.Dial = func(ctx context.Context, network,
 				address string) (net.Conn, error) {
+				ns, err := argv.resolveNameserver(ctx)
+				if err != nil {
+					return nil, err
+				}
 				d := net.Dialer{
 					LocalAddr: &net.UDPAddr{IP: a},
 					Timeout:   ift.interval,
 				}
-				return d.DialContext(ctx, "udp", argv.nameserver())
+				return d.DialContext(ctx, "udp", ns)
 			}
 		case resolv6:
 			r.Dial = func(ctx context.Context, network,
 				address string) (net.Conn, error) {
+				ns, err := argv.resolveNameserver(ctx)
+				if err != nil {
+					return nil, err
+				}
 				d := net.Dialer{
 					Timeout: ift.interval,
 				}
-				return d.DialContext(ctx, "udp6", argv.nameserver())
+				return d.DialContext(ctx, "udp6", ns)
 			}
 		}
 
@@ -338,91 +499,67 @@ func (argv *argvT) resolv(ift ifT, addr []net.IP) (string, error) {
 			fallthrough
 		case resolv6:
 			ctx := context.Background()
-			ipaddr, err := argv.lookup(ctx, &r)
+			n, err := pickAnswer(argv.lookup(ctx, &r))
 			if err != nil {
 				if argv.verbose > 0 {
 					argv.stderr.Println(a, err)
 				}
 				continue
 			}
-			if len(ipaddr) == 0 {
+			fmt.Println(ift.strategy, n)
+			return n, nil
+		case dot4:
+			fallthrough
+		case dot6:
+			ctx := context.Background()
+			n, err := pickAnswer(argv.lookupDoT(ctx, ift.strategy, a, ift.interval))
+			if err != nil {
 				if argv.verbose > 0 {
-					argv.stderr.Println(a, errInvalidAddress)
+					argv.stderr.Println(a, err)
 				}
 				continue
 			}
-			if net.ParseIP(ipaddr[0]) == nil {
+			fmt.Println(ift.strategy, n)
+			return n, nil
+		case doh:
+			ctx := context.Background()
+			n, err := pickAnswer(argv.lookupDoH(ctx, a, ift.interval))
+			if err != nil {
 				if argv.verbose > 0 {
-					argv.stderr.Println(a, errInvalidAddress)
+					argv.stderr.Println(a, err)
 				}
 				continue
 			}
-			fmt.Println(ift.strategy, ipaddr)
-			return ipaddr[0], nil
+			fmt.Println(ift.strategy, n)
+			return n, nil
 		}
 	}
 	return "", errNoValidAddresses
 }
 
-func (argv *argvT) publish(label, ipaddr string) error {
-	ip := net.ParseIP(ipaddr)
-	if ip == nil {
-		return nil
-	}
-	rtype := "A"
-	if ip.To4() == nil {
-		rtype = "AAAA"
-	}
-	u := fmt.Sprintf("https://dns.api.gandi.net/api/v5/domains/%s/records/%s/%s",
-		argv.domain,
-		label,
-		rtype,
-	)
-
-	h := make(http.Header)
-	h.Set("Content-Type", "application/json")
-	h.Set("X-Api-Key", argv.apikey)
-
-	body := fmt.Sprintf(
-		"{\"rrset_ttl\": %d, \"rrset_values\":[\"%s\"]}",
-		argv.ttl,
-		ipaddr,
-	)
-
-	ctx := context.Background()
-	r, err := http.NewRequestWithContext(
-		ctx,
-		"PUT",
-		u,
-		bytes.NewBufferString(body),
-	)
+// pickAnswer validates a lookup result, returning the first usable address.
+func pickAnswer(addr []string, err error) (string, error) {
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	r.Header = h
-
-	if argv.verbose > 0 {
-		fmt.Printf("%+v\n", r)
+	if len(addr) == 0 {
+		return "", errInvalidAddress
 	}
-
-	if argv.dryrun {
-		return nil
-	}
-
-	c := &http.Client{}
-	resp, err := c.Do(r)
-	if err != nil {
-		return err
+	if net.ParseIP(addr[0]) == nil {
+		return "", errInvalidAddress
 	}
+	return addr[0], nil
+}
 
-	defer resp.Body.Close()
-	rbody, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+// resolveNameserver returns the service's nameserver, bootstrapping it
+// through argv.bootstrap when configured so that hostname-form nameservers
+// never fall back to the system resolver.
+func (argv *argvT) resolveNameserver(ctx context.Context) (string, error) {
+	ns := argv.nameserver()
+	if argv.bootstrap == nil {
+		return ns, nil
 	}
-	fmt.Println(string(rbody))
-	return nil
+	return argv.bootstrap.resolve(ctx, ns)
 }
 
 func (argv *argvT) nameserver() string {
@@ -0,0 +1,57 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+
+//go:build linux
+
+package main
+
+import (
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// IFA_FLAGS bits from <linux/if_addr.h>, a stable kernel ABI not exposed
+// by golang.org/x/sys/unix.
+const (
+	ifaFTemporary  = 0x01
+	ifaFDeprecated = 0x20
+)
+
+// isTemporaryAddr reports whether ip is a temporary or deprecated IPv6
+// address (RFC 4941 privacy extensions) on interface name, read via
+// netlink IFA_FLAGS.
+func isTemporaryAddr(name string, ip net.IP) bool {
+	if ip.To4() != nil {
+		return false
+	}
+
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return false
+	}
+
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_V6)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range addrs {
+		if !a.IP.Equal(ip) {
+			continue
+		}
+		return a.Flags&(ifaFTemporary|ifaFDeprecated) != 0
+	}
+	return false
+}
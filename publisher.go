@@ -0,0 +1,68 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	Gandi    = "gandi"
+	Route53  = "route53"
+	DNSimple = "dnsimple"
+	RFC2136  = "rfc2136"
+)
+
+// errRecordNotFound is returned by Publisher.Get when the provider has no
+// record for the requested label, so callers can tell "nothing published
+// yet" apart from a lookup failure.
+var errRecordNotFound = errors.New("record not found")
+
+// Publisher updates and inspects a DNS record at a provider.
+type Publisher interface {
+	Publish(ctx context.Context, label, rtype string, ttl int, value string) error
+	// Get returns the record type and value currently published for label,
+	// or errRecordNotFound if the provider has no such record.
+	Get(ctx context.Context, label string) (rtype, value string, err error)
+}
+
+type publisherFactory func(argv *argvT) (Publisher, error)
+
+var publishers = map[string]publisherFactory{}
+
+func registerPublisher(provider string, factory publisherFactory) {
+	publishers[provider] = factory
+}
+
+func newPublisher(provider string, argv *argvT) (Publisher, error) {
+	factory, ok := publishers[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errInvalidProvider, provider)
+	}
+	return factory(argv)
+}
+
+// splitProvider extracts a dnsup://<provider>:// URL scheme prefix from
+// domain, e.g. "cloudflare://example.com", falling back to provider when
+// domain has no scheme.
+func splitProvider(domain, provider string) (string, string) {
+	scheme, rest, ok := strings.Cut(domain, "://")
+	if !ok {
+		return provider, domain
+	}
+	return strings.ToLower(scheme), rest
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import "testing"
+
+func TestSplitProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		domain       string
+		provider     string
+		wantProvider string
+		wantDomain   string
+	}{
+		{
+			name:         "mixed-case scheme is lowercased",
+			domain:       "Cloudflare://example.com",
+			provider:     "route53",
+			wantProvider: "cloudflare",
+			wantDomain:   "example.com",
+		},
+		{
+			name:         "lowercase scheme is unchanged",
+			domain:       "gandi://example.com",
+			provider:     "route53",
+			wantProvider: "gandi",
+			wantDomain:   "example.com",
+		},
+		{
+			name:         "no scheme falls back to provider",
+			domain:       "example.com",
+			provider:     "route53",
+			wantProvider: "route53",
+			wantDomain:   "example.com",
+		},
+		{
+			name:         "no scheme with empty provider falls back to empty",
+			domain:       "example.com",
+			provider:     "",
+			wantProvider: "",
+			wantDomain:   "example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotProvider, gotDomain := splitProvider(tt.domain, tt.provider)
+			if gotProvider != tt.wantProvider || gotDomain != tt.wantDomain {
+				t.Errorf("splitProvider(%q, %q) = %q, %q, want %q, %q",
+					tt.domain, tt.provider, gotProvider, gotDomain, tt.wantProvider, tt.wantDomain)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+var errUpdateRejected = errors.New("rfc2136: update rejected")
+
+func init() {
+	registerPublisher(RFC2136, newRFC2136Publisher)
+}
+
+// rfc2136Publisher publishes records as TSIG-signed RFC 2136 DNS UPDATE
+// messages, driving an authoritative server (BIND, Knot, PowerDNS, ...)
+// directly without a provider HTTP API. The server address, TSIG key name
+// and algorithm are read from DNSUP_RFC2136_SERVER, DNSUP_RFC2136_KEYNAME
+// and DNSUP_RFC2136_ALGORITHM; the base64 TSIG secret falls back to -apikey
+// if DNSUP_RFC2136_KEY is unset, consistent with the other backends.
+type rfc2136Publisher struct {
+	domain    string
+	server    string
+	keyname   string
+	secret    string
+	algorithm string
+}
+
+func newRFC2136Publisher(argv *argvT) (Publisher, error) {
+	return &rfc2136Publisher{
+		domain:    argv.domain,
+		server:    getenv("DNSUP_RFC2136_SERVER", ""),
+		keyname:   getenv("DNSUP_RFC2136_KEYNAME", ""),
+		secret:    getenv("DNSUP_RFC2136_KEY", argv.apikey),
+		algorithm: getenv("DNSUP_RFC2136_ALGORITHM", dns.HmacSHA256),
+	}, nil
+}
+
+func (p *rfc2136Publisher) Publish(ctx context.Context, label, rtype string, ttl int, value string) error {
+	zone := dns.Fqdn(p.domain)
+	name := dns.Fqdn(recordName(label, p.domain))
+
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, ttl, rtype, value))
+	if err != nil {
+		return err
+	}
+
+	old, err := dns.NewRR(fmt.Sprintf("%s IN %s", name, rtype))
+	if err != nil {
+		return err
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(zone)
+	m.RemoveRRset([]dns.RR{old})
+	m.Insert([]dns.RR{rr})
+
+	keyname := dns.Fqdn(p.keyname)
+	m.SetTsig(keyname, p.algorithm, 300, 0)
+
+	c := new(dns.Client)
+	c.TsigSecret = map[string]string{keyname: p.secret}
+
+	r, _, err := c.ExchangeContext(ctx, m, p.server)
+	if err != nil {
+		return err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("%w: %s", errUpdateRejected, dns.RcodeToString[r.Rcode])
+	}
+	return nil
+}
+
+// Get queries the authoritative server directly, since there is no
+// provider API to ask instead.
+func (p *rfc2136Publisher) Get(ctx context.Context, label string) (string, string, error) {
+	name := dns.Fqdn(recordName(label, p.domain))
+	c := new(dns.Client)
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+
+		r, _, err := c.ExchangeContext(ctx, m, p.server)
+		if err != nil {
+			return "", "", err
+		}
+		for _, rr := range r.Answer {
+			switch v := rr.(type) {
+			case *dns.A:
+				return "A", v.A.String(), nil
+			case *dns.AAAA:
+				return "AAAA", v.AAAA.String(), nil
+			}
+		}
+	}
+	return "", "", errRecordNotFound
+}
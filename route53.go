@@ -0,0 +1,137 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+var errHostedZoneNotFound = errors.New("hosted zone not found")
+
+func init() {
+	registerPublisher(Route53, newRoute53Publisher)
+}
+
+// route53Publisher publishes records using the AWS Route 53 API. Credentials
+// are resolved through the standard AWS SDK chain (environment variables,
+// shared config/credentials files, or an instance/container role); the
+// hosted zone ID can be pinned with DNSUP_ROUTE53_ZONE_ID to skip the lookup
+// by domain name.
+type route53Publisher struct {
+	api    *route53.Client
+	domain string
+	zoneID string
+}
+
+func newRoute53Publisher(argv *argvT) (Publisher, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &route53Publisher{
+		api:    route53.NewFromConfig(cfg),
+		domain: argv.domain,
+		zoneID: getenv("DNSUP_ROUTE53_ZONE_ID", ""),
+	}, nil
+}
+
+func (p *route53Publisher) Publish(ctx context.Context, label, rtype string, ttl int, value string) error {
+	zoneID, err := p.hostedZoneID(ctx)
+	if err != nil {
+		return err
+	}
+
+	name := recordName(label, p.domain)
+
+	_, err = p.api.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch: &types.ChangeBatch{
+			Changes: []types.Change{
+				{
+					Action: types.ChangeActionUpsert,
+					ResourceRecordSet: &types.ResourceRecordSet{
+						Name:            aws.String(name),
+						Type:            types.RRType(rtype),
+						TTL:             aws.Int64(int64(ttl)),
+						ResourceRecords: []types.ResourceRecord{{Value: aws.String(value)}},
+					},
+				},
+			},
+		},
+	})
+	return err
+}
+
+func (p *route53Publisher) Get(ctx context.Context, label string) (string, string, error) {
+	zoneID, err := p.hostedZoneID(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	name := recordName(label, p.domain)
+
+	out, err := p.api.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneID),
+		StartRecordName: aws.String(name),
+		MaxItems:        aws.Int32(2),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, rrset := range out.ResourceRecordSets {
+		if aws.ToString(rrset.Name) != dnsName(name) {
+			continue
+		}
+		if rrset.Type != types.RRTypeA && rrset.Type != types.RRTypeAaaa {
+			continue
+		}
+		if len(rrset.ResourceRecords) == 0 {
+			continue
+		}
+		return string(rrset.Type), aws.ToString(rrset.ResourceRecords[0].Value), nil
+	}
+	return "", "", errRecordNotFound
+}
+
+// dnsName appends the trailing dot Route 53 uses for fully qualified names.
+func dnsName(name string) string {
+	return name + "."
+}
+
+func (p *route53Publisher) hostedZoneID(ctx context.Context) (string, error) {
+	if p.zoneID != "" {
+		return p.zoneID, nil
+	}
+
+	out, err := p.api.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(p.domain),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, zone := range out.HostedZones {
+		if aws.ToString(zone.Name) == p.domain+"." {
+			return aws.ToString(zone.Id), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", errHostedZoneNotFound, p.domain)
+}
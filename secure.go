@@ -0,0 +1,185 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var errUnsupportedTransport = errors.New("service does not support encrypted transport")
+
+// secureRootCAs overrides the trust store used to verify DoT/DoH TLS
+// certificates; nil (the default) uses the system certificate pool. Tests
+// substitute a private CA to exercise the TLS handshake against local stub
+// servers.
+var secureRootCAs *x509.CertPool
+
+// secureServiceT carries the metadata needed to run a whoami lookup over
+// DNS-over-TLS or DNS-over-HTTPS for a public IP discovery service. Not
+// every service in the nameserver()/lookup() table offers DoT/DoH, so the
+// dot/doh fields are left empty where unsupported.
+type secureServiceT struct {
+	qname string
+	qtype uint16
+	dot   string // DoT address for dot4, host:853
+	dot6  string // DoT address for dot6, [host]:853
+	sni   string // TLS ServerName presented by dot/dot6
+	doh   string // DoH /dns-query endpoint
+}
+
+var secureServices = map[string]secureServiceT{
+	Cloudflare: {
+		qname: "whoami.cloudflare.com.",
+		qtype: dns.TypeTXT,
+		dot:   "1.1.1.1:853",
+		dot6:  "[2606:4700:4700::1111]:853",
+		sni:   "cloudflare-dns.com",
+		doh:   "https://cloudflare-dns.com/dns-query",
+	},
+	Google: {
+		qname: "o-o.myaddr.l.google.com.",
+		qtype: dns.TypeTXT,
+		dot:   "8.8.8.8:853",
+		dot6:  "[2001:4860:4860::8888]:853",
+		sni:   "dns.google",
+		doh:   "https://dns.google/dns-query",
+	},
+	OpenDNS: {
+		qname: "myip.opendns.com.",
+		qtype: dns.TypeA,
+		dot:   "208.67.222.222:853",
+		dot6:  "[2620:119:35::35]:853",
+		sni:   "dns.opendns.com",
+		doh:   "https://doh.opendns.com/dns-query",
+	},
+}
+
+func (argv *argvT) lookupDoT(ctx context.Context, strategy Strategy, local net.IP, timeout time.Duration) ([]string, error) {
+	svc, ok := secureServices[argv.service]
+	if !ok || svc.dot == "" {
+		return nil, fmt.Errorf("%w: %s: dot", errUnsupportedTransport, argv.service)
+	}
+
+	addr := svc.dot
+	if strategy == dot6 {
+		if svc.dot6 == "" {
+			return nil, fmt.Errorf("%w: %s: dot6", errUnsupportedTransport, argv.service)
+		}
+		addr = svc.dot6
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(svc.qname, svc.qtype)
+
+	c := &dns.Client{
+		Net:       "tcp-tls",
+		Timeout:   timeout,
+		TLSConfig: &tls.Config{ServerName: svc.sni, MinVersion: tls.VersionTLS12, RootCAs: secureRootCAs},
+		Dialer:    &net.Dialer{LocalAddr: &net.TCPAddr{IP: local}},
+	}
+
+	r, _, err := c.ExchangeContext(ctx, m, addr)
+	if err != nil {
+		return nil, err
+	}
+	return answers(r, svc.qtype)
+}
+
+func (argv *argvT) lookupDoH(ctx context.Context, local net.IP, timeout time.Duration) ([]string, error) {
+	svc, ok := secureServices[argv.service]
+	if !ok || svc.doh == "" {
+		return nil, fmt.Errorf("%w: %s: doh", errUnsupportedTransport, argv.service)
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(svc.qname, svc.qtype)
+	wire, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{LocalAddr: &net.TCPAddr{IP: local}, Timeout: timeout}
+	dial := dialer.DialContext
+	if argv.bootstrap != nil {
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			resolved, err := argv.bootstrap.resolve(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, resolved)
+		}
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:     dial,
+			TLSClientConfig: &tls.Config{RootCAs: secureRootCAs},
+		},
+	}
+
+	u := fmt.Sprintf("%s?dns=%s", svc.doh, base64.RawURLEncoding.EncodeToString(wire))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	reply := new(dns.Msg)
+	if err := reply.Unpack(body); err != nil {
+		return nil, err
+	}
+	return answers(reply, svc.qtype)
+}
+
+// answers extracts the whoami value from a DNS response, matching the
+// TXT/A handling of the plaintext lookup() table.
+func answers(m *dns.Msg, qtype uint16) ([]string, error) {
+	var out []string
+	for _, rr := range m.Answer {
+		switch v := rr.(type) {
+		case *dns.TXT:
+			out = append(out, v.Txt...)
+		case *dns.A:
+			out = append(out, v.A.String())
+		case *dns.AAAA:
+			out = append(out, v.AAAA.String())
+		}
+	}
+	if len(out) == 0 {
+		return nil, errNoValidAddresses
+	}
+	return out, nil
+}
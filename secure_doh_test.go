@@ -0,0 +1,152 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startTestDoHServer runs a local HTTPS server answering every DoH query
+// for qname with a TXT record of value, counting how many requests it has
+// handled.
+func startTestDoHServer(t *testing.T, cert tls.Certificate, qname, value string) (addr string, hits *int32) {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("tls.Listen: %s", err)
+	}
+
+	var n int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dns-query", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+
+		wire, err := base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		q := new(dns.Msg)
+		if err := q.Unpack(wire); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		m := new(dns.Msg)
+		m.SetReply(q)
+		if rr, err := dns.NewRR(qname + " 0 IN TXT " + "\"" + value + "\""); err == nil {
+			m.Answer = append(m.Answer, rr)
+		}
+		reply, err := m.Pack()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(reply)
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return ln.Addr().String(), &n
+}
+
+func TestLookupDoHResolvesHostThroughBootstrap(t *testing.T) {
+	cert, pool := generateTestCert(t, "dnsup-test.invalid")
+	t.Cleanup(func() { secureRootCAs = nil })
+	secureRootCAs = pool
+
+	const qname = "whoami.dnsup-test.invalid."
+	dohAddr, dohHits := startTestDoHServer(t, cert, qname, "192.0.2.3")
+	_, dohPort, err := net.SplitHostPort(dohAddr)
+	if err != nil {
+		t.Fatalf("SplitHostPort: %s", err)
+	}
+
+	// The authoritative stub only answers for the DoH hostname: if
+	// lookupDoH fell back to the real system resolver instead of
+	// argv.bootstrap, "dnsup-test.invalid" would fail to resolve and the
+	// request would never reach dohAddr.
+	nsAddr, nsHits := startTestResolver(t, "dnsup-test.invalid.", "127.0.0.1", 3600)
+
+	const svcName = "dnsup-test-doh"
+	secureServices[svcName] = secureServiceT{
+		qname: qname,
+		qtype: dns.TypeTXT,
+		doh:   "https://dnsup-test.invalid:" + dohPort + "/dns-query",
+	}
+	t.Cleanup(func() { delete(secureServices, svcName) })
+
+	argv := &argvT{service: svcName, bootstrap: newBootstrapResolver([]string{nsAddr})}
+
+	got, err := argv.lookupDoH(context.Background(), nil, 2*time.Second)
+	if err != nil {
+		t.Fatalf("lookupDoH: %s", err)
+	}
+	if len(got) != 1 || got[0] != "192.0.2.3" {
+		t.Fatalf("lookupDoH = %v, want [192.0.2.3]", got)
+	}
+	if atomic.LoadInt32(dohHits) != 1 {
+		t.Fatalf("DoH server hit %d times, want 1", *dohHits)
+	}
+	if atomic.LoadInt32(nsHits) != 1 {
+		t.Fatalf("bootstrap resolver hit %d times, want 1", *nsHits)
+	}
+}
+
+func TestLookupDoHWithoutBootstrapUsesDefaultDialer(t *testing.T) {
+	cert, pool := generateTestCert(t, "localhost")
+	t.Cleanup(func() { secureRootCAs = nil })
+	secureRootCAs = pool
+
+	const qname = "whoami.dnsup-test.invalid."
+	dohAddr, dohHits := startTestDoHServer(t, cert, qname, "192.0.2.4")
+	_, dohPort, err := net.SplitHostPort(dohAddr)
+	if err != nil {
+		t.Fatalf("SplitHostPort: %s", err)
+	}
+
+	const svcName = "dnsup-test-doh-no-bootstrap"
+	secureServices[svcName] = secureServiceT{
+		qname: qname,
+		qtype: dns.TypeTXT,
+		doh:   "https://localhost:" + dohPort + "/dns-query",
+	}
+	t.Cleanup(func() { delete(secureServices, svcName) })
+
+	argv := &argvT{service: svcName}
+
+	got, err := argv.lookupDoH(context.Background(), nil, 2*time.Second)
+	if err != nil {
+		t.Fatalf("lookupDoH: %s", err)
+	}
+	if len(got) != 1 || got[0] != "192.0.2.4" {
+		t.Fatalf("lookupDoH = %v, want [192.0.2.4]", got)
+	}
+	if atomic.LoadInt32(dohHits) != 1 {
+		t.Fatalf("DoH server hit %d times, want 1", *dohHits)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// generateTestCert creates a self-signed certificate for dnsName and a
+// CertPool that trusts it, so tests can drive a real TLS handshake against
+// a local stub server instead of skipping verification.
+func generateTestCert(t *testing.T, dnsName string) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate: %s", err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate: %s", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return cert, pool
+}
+
+// startTestDoTServer runs a local DNS-over-TLS server answering every query
+// for qname with a TXT record of value, counting how many queries it has
+// handled.
+func startTestDoTServer(t *testing.T, cert tls.Certificate, qname, value string) (addr string, hits *int32) {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("tls.Listen: %s", err)
+	}
+
+	var n int32
+	mux := dns.NewServeMux()
+	mux.HandleFunc(qname, func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&n, 1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		rr, err := dns.NewRR(qname + " 0 IN TXT " + "\"" + value + "\"")
+		if err == nil {
+			m.Answer = append(m.Answer, rr)
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	srv := &dns.Server{Listener: ln, Net: "tcp-tls", Handler: mux}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	return ln.Addr().String(), &n
+}
+
+func TestLookupDoTDialsMatchingStrategyEndpoint(t *testing.T) {
+	cert, pool := newTestServerCert(t)
+	t.Cleanup(func() { secureRootCAs = nil })
+	secureRootCAs = pool
+
+	const qname = "whoami.dnsup-test.invalid."
+	dot4Addr, dot4Hits := startTestDoTServer(t, cert, qname, "192.0.2.1")
+	dot6Addr, dot6Hits := startTestDoTServer(t, cert, qname, "192.0.2.2")
+
+	const svcName = "dnsup-test-dot"
+	secureServices[svcName] = secureServiceT{
+		qname: qname,
+		qtype: dns.TypeTXT,
+		dot:   dot4Addr,
+		dot6:  dot6Addr,
+		sni:   "dnsup-test.invalid",
+	}
+	t.Cleanup(func() { delete(secureServices, svcName) })
+
+	argv := &argvT{service: svcName}
+
+	got, err := argv.lookupDoT(context.Background(), dot4, nil, 2*time.Second)
+	if err != nil {
+		t.Fatalf("lookupDoT(dot4): %s", err)
+	}
+	if len(got) != 1 || got[0] != "192.0.2.1" {
+		t.Fatalf("lookupDoT(dot4) = %v, want [192.0.2.1]", got)
+	}
+	if atomic.LoadInt32(dot4Hits) != 1 || atomic.LoadInt32(dot6Hits) != 0 {
+		t.Fatalf("dot4 strategy hit dot4=%d dot6=%d, want dot4=1 dot6=0", *dot4Hits, *dot6Hits)
+	}
+
+	got, err = argv.lookupDoT(context.Background(), dot6, nil, 2*time.Second)
+	if err != nil {
+		t.Fatalf("lookupDoT(dot6): %s", err)
+	}
+	if len(got) != 1 || got[0] != "192.0.2.2" {
+		t.Fatalf("lookupDoT(dot6) = %v, want [192.0.2.2]", got)
+	}
+	if atomic.LoadInt32(dot6Hits) != 1 {
+		t.Fatalf("dot6 strategy hit dot6=%d, want 1", *dot6Hits)
+	}
+}
+
+func TestLookupDoTMissingDot6Errors(t *testing.T) {
+	const svcName = "dnsup-test-dot-no-v6"
+	secureServices[svcName] = secureServiceT{
+		qname: "whoami.dnsup-test.invalid.",
+		qtype: dns.TypeTXT,
+		dot:   "127.0.0.1:1",
+	}
+	t.Cleanup(func() { delete(secureServices, svcName) })
+
+	argv := &argvT{service: svcName}
+	if _, err := argv.lookupDoT(context.Background(), dot6, nil, time.Second); err == nil {
+		t.Fatal("lookupDoT(dot6): expected error when no dot6 address is configured")
+	}
+}
+
+// newTestServerCert generates a self-signed certificate for ServerName
+// "dnsup-test.invalid" plus a pool that trusts it, so secure_test.go can
+// verify DoT/DoH TLS handshakes against local stub servers without relying
+// on the system certificate pool.
+func newTestServerCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	return generateTestCert(t, "dnsup-test.invalid")
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordState is the persisted view of a label's last published record.
+type recordState struct {
+	Type      string    `json:"type"`
+	Value     string    `json:"value"`
+	TTL       int       `json:"ttl"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// stateStore persists the last-published value per label to disk as JSON,
+// so a restart doesn't have to re-publish records that haven't changed.
+type stateStore struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]recordState
+}
+
+func loadStateStore(path string) (*stateStore, error) {
+	s := &stateStore{path: path, records: make(map[string]recordState)}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&s.records); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *stateStore) get(label string) (recordState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.records[label]
+	return r, ok
+}
+
+func (s *stateStore) set(label string, r recordState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[label] = r
+	return s.save()
+}
+
+// save writes the state atomically via a rename, and must be called with
+// s.mu held.
+func (s *stateStore) save() error {
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(s.records); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// reconcile seeds the last-published value for ift.label by querying the
+// provider directly via Publisher.Get, so a record changed out-of-band
+// since the last run is always detected on startup. The persisted cache
+// entry, if any, is used only as a fallback when the provider can't be
+// reached.
+func (argv *argvT) reconcile(ctx context.Context, ift ifT) string {
+	_, value, err := argv.publisher.Get(ctx, ift.label)
+	if err == nil {
+		return value
+	}
+	if argv.verbose > 0 && !errors.Is(err, errRecordNotFound) {
+		argv.stderr.Printf("reconcile: %+v: %s\n", ift, err)
+	}
+	if argv.state != nil {
+		if cached, ok := argv.state.get(ift.label); ok {
+			return cached.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+// stubPublisher is a Publisher whose Get result is configured per test and
+// whose call count is recorded, so reconcile's provider-querying behavior
+// can be asserted directly.
+type stubPublisher struct {
+	getCalls int
+	rtype    string
+	value    string
+	err      error
+}
+
+func (p *stubPublisher) Publish(context.Context, string, string, int, string) error {
+	return nil
+}
+
+func (p *stubPublisher) Get(context.Context, string) (string, string, error) {
+	p.getCalls++
+	return p.rtype, p.value, p.err
+}
+
+func newTestArgv(pub Publisher, state *stateStore) *argvT {
+	return &argvT{
+		publisher: pub,
+		state:     state,
+		stdout:    log.New(io.Discard, "", 0),
+		stderr:    log.New(io.Discard, "", 0),
+	}
+}
+
+func TestReconcileAlwaysQueriesProviderEvenWithFreshCache(t *testing.T) {
+	pub := &stubPublisher{rtype: "A", value: "203.0.113.9"}
+	state := &stateStore{path: t.TempDir() + "/state.json", records: map[string]recordState{
+		"home.example.com": {Type: "A", Value: "198.51.100.1", UpdatedAt: time.Now()},
+	}}
+	argv := newTestArgv(pub, state)
+
+	got := argv.reconcile(context.Background(), ifT{label: "home.example.com", interval: time.Hour})
+	if pub.getCalls != 1 {
+		t.Fatalf("Publisher.Get called %d times, want 1", pub.getCalls)
+	}
+	if got != "203.0.113.9" {
+		t.Fatalf("reconcile = %q, want live provider value %q", got, "203.0.113.9")
+	}
+}
+
+func TestReconcileFallsBackToCacheOnProviderError(t *testing.T) {
+	pub := &stubPublisher{err: errors.New("provider unreachable")}
+	state := &stateStore{path: t.TempDir() + "/state.json", records: map[string]recordState{
+		"home.example.com": {Type: "A", Value: "198.51.100.1", UpdatedAt: time.Now()},
+	}}
+	argv := newTestArgv(pub, state)
+
+	got := argv.reconcile(context.Background(), ifT{label: "home.example.com", interval: time.Hour})
+	if pub.getCalls != 1 {
+		t.Fatalf("Publisher.Get called %d times, want 1", pub.getCalls)
+	}
+	if got != "198.51.100.1" {
+		t.Fatalf("reconcile = %q, want cached fallback %q", got, "198.51.100.1")
+	}
+}
+
+func TestReconcileReturnsEmptyWithNoCacheAndProviderError(t *testing.T) {
+	pub := &stubPublisher{err: errRecordNotFound}
+	argv := newTestArgv(pub, nil)
+
+	got := argv.reconcile(context.Background(), ifT{label: "home.example.com", interval: time.Hour})
+	if pub.getCalls != 1 {
+		t.Fatalf("Publisher.Get called %d times, want 1", pub.getCalls)
+	}
+	if got != "" {
+		t.Fatalf("reconcile = %q, want empty string", got)
+	}
+}
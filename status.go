@@ -0,0 +1,171 @@
+// Copyright (c) 2020-2022, Michael Santos <michael.santos@gmail.com>
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+// WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+// MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+// ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+// WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+// ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+// OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ifStatus tracks the observable state of a single interface/label pair:
+// the last discovered address, the last successful publish, and counters
+// and errors for both steps, so an operator can tell a missing interface
+// address apart from a provider rejecting a publish.
+type ifStatus struct {
+	mu sync.Mutex
+
+	ift ifT
+
+	ip          string
+	ttl         int
+	lastSuccess time.Time
+
+	lookups     uint64
+	lookupErr   string
+	lookupErrAt time.Time
+	lookupFails uint64
+
+	publishes    uint64
+	publishErr   string
+	publishErrAt time.Time
+	publishFails uint64
+}
+
+func (s *ifStatus) recordLookup(ip string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.lookupFails++
+		s.lookupErr = err.Error()
+		s.lookupErrAt = time.Now()
+		return
+	}
+	s.lookups++
+	s.ip = ip
+	s.lastSuccess = time.Now()
+}
+
+func (s *ifStatus) recordPublish(ttl int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.publishFails++
+		s.publishErr = err.Error()
+		s.publishErrAt = time.Now()
+		return
+	}
+	s.publishes++
+	s.ttl = ttl
+	s.lastSuccess = time.Now()
+}
+
+// statusRegistry is the set of ifStatus trackers, keyed by label.
+type statusRegistry struct {
+	mu      sync.Mutex
+	byLabel map[string]*ifStatus
+}
+
+func newStatusRegistry() *statusRegistry {
+	return &statusRegistry{byLabel: make(map[string]*ifStatus)}
+}
+
+func (r *statusRegistry) get(ift ifT) *ifStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.byLabel[ift.label]
+	if !ok {
+		s = &ifStatus{ift: ift}
+		r.byLabel[ift.label] = s
+	}
+	return s
+}
+
+// statusEntry is the JSON/Prometheus view of an ifStatus snapshot.
+type statusEntry struct {
+	Interface      string    `json:"interface"`
+	Label          string    `json:"label"`
+	Strategy       string    `json:"strategy"`
+	Interval       string    `json:"interval"`
+	IP             string    `json:"ip,omitempty"`
+	TTL            int       `json:"ttl,omitempty"`
+	LastSuccess    time.Time `json:"last_success,omitempty"`
+	Lookups        uint64    `json:"lookups"`
+	LookupError    string    `json:"lookup_error,omitempty"`
+	LookupErrorAt  time.Time `json:"lookup_error_at,omitempty"`
+	LookupFails    uint64    `json:"lookup_errors"`
+	Publishes      uint64    `json:"publishes"`
+	PublishError   string    `json:"publish_error,omitempty"`
+	PublishErrorAt time.Time `json:"publish_error_at,omitempty"`
+	PublishFails   uint64    `json:"publish_errors"`
+}
+
+func (r *statusRegistry) snapshot() []statusEntry {
+	r.mu.Lock()
+	labels := make([]string, 0, len(r.byLabel))
+	for label := range r.byLabel {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	entries := make([]statusEntry, 0, len(labels))
+	for _, label := range labels {
+		entries = append(entries, r.byLabel[label].snapshot())
+	}
+	r.mu.Unlock()
+	return entries
+}
+
+func (s *ifStatus) snapshot() statusEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return statusEntry{
+		Interface:      s.ift.name,
+		Label:          s.ift.label,
+		Strategy:       strategyName(s.ift.strategy),
+		Interval:       s.ift.interval.String(),
+		IP:             s.ip,
+		TTL:            s.ttl,
+		LastSuccess:    s.lastSuccess,
+		Lookups:        s.lookups,
+		LookupError:    s.lookupErr,
+		LookupErrorAt:  s.lookupErrAt,
+		LookupFails:    s.lookupFails,
+		Publishes:      s.publishes,
+		PublishError:   s.publishErr,
+		PublishErrorAt: s.publishErrAt,
+		PublishFails:   s.publishFails,
+	}
+}
+
+func strategyName(s Strategy) string {
+	switch s {
+	case inet4:
+		return "inet4"
+	case inet6:
+		return "inet6"
+	case resolv4:
+		return "resolv4"
+	case resolv6:
+		return "resolv6"
+	case dot4:
+		return "dot4"
+	case dot6:
+		return "dot6"
+	case doh:
+		return "doh"
+	default:
+		return "unknown"
+	}
+}